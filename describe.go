@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	_ "image/png"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// describeCmd is the original (pre-subcommand) behavior: describe an image
+// as a textual prompt.
+var describeCmd = &cobra.Command{
+	Use:   "describe [flags] <image-file>",
+	Short: "Describe an image as a prompt that reconstructs it pixel by pixel",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDescribe,
+}
+
+func init() {
+	addImageFlags(describeCmd)
+	describeCmd.Flags().String("format", "prose", "prompt output format: prose, json, yaml, rle")
+	describeCmd.Flags().String("encoding", "rle-1d", "row encoding: rle-1d, rle-2d, rects")
+	describeCmd.Flags().Int("max-rects", 0, "max rectangles for --encoding rects before falling back to rle-1d (0 = default cap)")
+}
+
+// runDescribe loads an image and constructs a run-length encoded prompt describing the image pixel by pixel.
+func runDescribe(cmd *cobra.Command, args []string) error {
+	dither, err := cmd.Flags().GetString("dither")
+	if err != nil {
+		return fmt.Errorf("get dither flag: %w", err)
+	}
+
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return fmt.Errorf("get format flag: %w", err)
+	}
+
+	encoding, err := cmd.Flags().GetString("encoding")
+	if err != nil {
+		return fmt.Errorf("get encoding flag: %w", err)
+	}
+
+	maxRects, err := cmd.Flags().GetInt("max-rects")
+	if err != nil {
+		return fmt.Errorf("get max-rects flag: %w", err)
+	}
+
+	preprocessOpts, err := preprocessOptionsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	renderer, err := parseRenderer(format)
+	if err != nil {
+		return fmt.Errorf("parse format: %w", err)
+	}
+
+	encoding, err = parseEncoding(encoding)
+	if err != nil {
+		return fmt.Errorf("parse encoding: %w", err)
+	}
+
+	// Open image file
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("open image file: %w", err)
+	}
+
+	defer f.Close()
+
+	// Decode image
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("decode image: %w", err)
+	}
+
+	img, err = preprocess(cmd.Context(), img, preprocessOpts)
+	if err != nil {
+		return fmt.Errorf("preprocess image: %w", err)
+	}
+
+	q, err := quantizerFromFlags(cmd, img)
+	if err != nil {
+		return fmt.Errorf("build quantizer: %w", err)
+	}
+
+	grid, err := quantizeGrid(cmd.Context(), img, q, dither)
+	if err != nil {
+		return fmt.Errorf("quantize image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	doc := newPromptDocument(bounds.Dx(), bounds.Dy(), paletteNames(q))
+	doc.Encoding = encoding
+
+	switch encoding {
+	case "rects":
+		rects, ok := encodeRects(grid, maxRects)
+		if !ok {
+			doc.Encoding = "rle-1d"
+			doc.Rows = encodeRLE1D(grid)
+		} else {
+			doc.Rects = rects
+		}
+	case "rle-2d":
+		doc.Rows = encodeRLE2D(grid)
+	default:
+		doc.Rows = encodeRLE1D(grid)
+	}
+
+	prompt, err := renderer.Render(cmd.Context(), doc)
+	if err != nil {
+		return fmt.Errorf("render prompt: %w", err)
+	}
+
+	fmt.Print(prompt)
+
+	return nil
+}
+
+// paletteNames returns the ordered color names of a quantizer's palette, or
+// nil if it doesn't expose one.
+func paletteNames(q Quantizer) []string {
+	pc, ok := q.(paletteColorer)
+	if !ok {
+		return nil
+	}
+
+	names := make([]string, len(pc.colors()))
+	for i, c := range pc.colors() {
+		names[i] = c.Name
+	}
+
+	return names
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log/slog"
+
+	applog "github.com/tja/image-to-prompt/log"
+)
+
+// quantizeGrid converts img into a grid of palette color names, one row per
+// image row, optionally pre-dithering against the quantizer's palette so
+// that multi-tone images degrade gracefully instead of banding.
+func quantizeGrid(ctx context.Context, img image.Image, q Quantizer, dither string) ([][]string, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	applog.FromContext(ctx).Debug("quantizing image",
+		slog.Int("width", width), slog.Int("height", height), slog.String("dither", dither))
+
+	grid := make([][]string, height)
+
+	switch dither {
+	case "", "none":
+		for y := 0; y < height; y++ {
+			grid[y] = make([]string, width)
+			for x := 0; x < width; x++ {
+				grid[y][x] = q.Name(img.At(bounds.Min.X+x, bounds.Min.Y+y))
+			}
+		}
+	case "floyd-steinberg":
+		pc, ok := q.(paletteColorer)
+		if !ok {
+			return nil, fmt.Errorf("palette does not support dithering")
+		}
+
+		floydSteinbergDither(img, q, pc.colors(), grid, width, height)
+	default:
+		return nil, fmt.Errorf("unknown dither mode %q", dither)
+	}
+
+	return grid, nil
+}
+
+// floydSteinbergDither quantizes img against palette, diffusing quantization
+// error to unprocessed neighbors using the classic Floyd-Steinberg weights
+// (7/16 right, 3/16 below-left, 5/16 below, 1/16 below-right).
+func floydSteinbergDither(img image.Image, q Quantizer, palette []namedColor, grid [][]string, width, height int) {
+	bounds := img.Bounds()
+
+	// Working buffer of RGB error-accumulated values, one per channel.
+	type rgb struct{ r, g, b float64 }
+
+	buf := make([][]rgb, height)
+	for y := 0; y < height; y++ {
+		buf[y] = make([]rgb, width)
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			buf[y][x] = rgb{float64(r >> 8), float64(g >> 8), float64(b >> 8)}
+		}
+	}
+
+	nearest := func(px rgb) (string, rgb) {
+		best := 0
+		bestDist := -1.0
+
+		for i, e := range palette {
+			er, eg, eb, _ := e.Color.RGBA()
+			dr := px.r - float64(er>>8)
+			dg := px.g - float64(eg>>8)
+			db := px.b - float64(eb>>8)
+			dist := dr*dr + dg*dg + db*db
+
+			if bestDist < 0 || dist < bestDist {
+				best = i
+				bestDist = dist
+			}
+		}
+
+		er, eg, eb, _ := palette[best].Color.RGBA()
+
+		return palette[best].Name, rgb{float64(er >> 8), float64(eg >> 8), float64(eb >> 8)}
+	}
+
+	diffuse := func(x, y int, errR, errG, errB, weight float64) {
+		if x < 0 || x >= width || y < 0 || y >= height {
+			return
+		}
+
+		buf[y][x].r += errR * weight
+		buf[y][x].g += errG * weight
+		buf[y][x].b += errB * weight
+	}
+
+	for y := 0; y < height; y++ {
+		grid[y] = make([]string, width)
+
+		for x := 0; x < width; x++ {
+			old := buf[y][x]
+			name, quantized := nearest(old)
+			grid[y][x] = name
+
+			errR := old.r - quantized.r
+			errG := old.g - quantized.g
+			errB := old.b - quantized.b
+
+			diffuse(x+1, y, errR, errG, errB, 7.0/16.0)
+			diffuse(x-1, y+1, errR, errG, errB, 3.0/16.0)
+			diffuse(x, y+1, errR, errG, errB, 5.0/16.0)
+			diffuse(x+1, y+1, errR, errG, errB, 1.0/16.0)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// TestQuantizeGridFloydSteinberg checks that dithering a flat mid-gray image
+// against a pure black/white palette produces a mix of both colors (error
+// diffusion breaking up what would otherwise band into a single color)
+// and that every row stays the right width.
+func TestQuantizeGridFloydSteinberg(t *testing.T) {
+	const width, height = 8, 8
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetGray(x, y, color.Gray{Y: 128})
+		}
+	}
+
+	q := bwQuantizer{threshold: defaultThreshold}
+
+	grid, err := quantizeGrid(context.Background(), img, q, "floyd-steinberg")
+	if err != nil {
+		t.Fatalf("quantizeGrid: %v", err)
+	}
+
+	if len(grid) != height {
+		t.Fatalf("got %d rows, want %d", len(grid), height)
+	}
+
+	seen := map[string]bool{}
+
+	for y, row := range grid {
+		if len(row) != width {
+			t.Fatalf("row %d: got %d columns, want %d", y, len(row), width)
+		}
+
+		for _, name := range row {
+			if name != "black" && name != "white" {
+				t.Fatalf("row %d: unexpected color %q", y, name)
+			}
+
+			seen[name] = true
+		}
+	}
+
+	if len(seen) != 2 {
+		t.Errorf("dithering a flat mid-gray image produced only %v, want both black and white", seen)
+	}
+}
+
+// TestQuantizeGridUnsupportedDither checks that dithering against a
+// quantizer that can't enumerate its palette fails loudly instead of
+// silently falling back to no dithering.
+func TestQuantizeGridUnsupportedDither(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+
+	_, err := quantizeGrid(context.Background(), img, noColorsQuantizer{}, "floyd-steinberg")
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+}
+
+// noColorsQuantizer is a Quantizer that doesn't implement paletteColorer.
+type noColorsQuantizer struct{}
+
+func (noColorsQuantizer) Name(color.Color) string { return "x" }
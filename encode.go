@@ -0,0 +1,152 @@
+package main
+
+import "fmt"
+
+// rect describes a solid-color axis-aligned block, used by the rects
+// encoding.
+type rect struct {
+	Row    int    `json:"row" yaml:"row"`
+	Col    int    `json:"col" yaml:"col"`
+	Width  int    `json:"width" yaml:"width"`
+	Height int    `json:"height" yaml:"height"`
+	Color  string `json:"color" yaml:"color"`
+}
+
+// defaultMaxRects is the safety cap applied when --max-rects is unset.
+const defaultMaxRects = 2000
+
+// encodeRLE1D compresses every row independently, the original behavior.
+func encodeRLE1D(grid [][]string) []encodedRow {
+	rows := make([]encodedRow, len(grid))
+	for y, line := range grid {
+		rows[y] = encodedRow{Runs: compressRuns(line)}
+	}
+
+	return rows
+}
+
+// encodeRLE2D is encodeRLE1D plus vertical deduplication: a row that's
+// pixel-for-pixel identical to the row directly above it is replaced with a
+// reference to the first row of that repeated block instead of repeating its
+// runs. A row that repeats a non-adjacent earlier row keeps its own runs.
+func encodeRLE2D(grid [][]string) []encodedRow {
+	rows := make([]encodedRow, len(grid))
+
+	for y, line := range grid {
+		if y > 0 {
+			ref := rows[y-1].SameAsRow
+			if ref == 0 {
+				ref = y // 1-based index of the previous row
+			}
+
+			if sameRow(grid[ref-1], line) {
+				rows[y] = encodedRow{SameAsRow: ref}
+
+				continue
+			}
+		}
+
+		rows[y] = encodedRow{Runs: compressRuns(line)}
+	}
+
+	return rows
+}
+
+// sameRow reports whether two rows hold identical color names.
+func sameRow(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// encodeRects decomposes grid into the fewest maximal axis-aligned
+// same-color rectangles it can find greedily: at each unvisited pixel it
+// grows the rectangle right while the whole column matches, then grows it
+// down while the whole row of that width matches. It reports false if more
+// than maxRects rectangles would be needed, so the caller can fall back to
+// rle-1d.
+func encodeRects(grid [][]string, maxRects int) ([]rect, bool) {
+	if maxRects <= 0 {
+		maxRects = defaultMaxRects
+	}
+
+	height := len(grid)
+	if height == 0 {
+		return nil, true
+	}
+
+	width := len(grid[0])
+
+	visited := make([][]bool, height)
+	for y := range visited {
+		visited[y] = make([]bool, width)
+	}
+
+	var rects []rect
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if visited[y][x] {
+				continue
+			}
+
+			color := grid[y][x]
+
+			// Grow right while every pixel in the row matches.
+			w := 1
+			for x+w < width && !visited[y][x+w] && grid[y][x+w] == color {
+				w++
+			}
+
+			// Grow down while the whole row of width w matches.
+			h := 1
+			for y+h < height && rowMatches(grid[y+h], visited[y+h], x, w, color) {
+				h++
+			}
+
+			for dy := 0; dy < h; dy++ {
+				for dx := 0; dx < w; dx++ {
+					visited[y+dy][x+dx] = true
+				}
+			}
+
+			rects = append(rects, rect{Row: y + 1, Col: x + 1, Width: w, Height: h, Color: color})
+			if len(rects) > maxRects {
+				return nil, false
+			}
+		}
+	}
+
+	return rects, true
+}
+
+// rowMatches reports whether row[col:col+w] is all color and unvisited.
+func rowMatches(row []string, visitedRow []bool, col, w int, color string) bool {
+	for i := col; i < col+w; i++ {
+		if visitedRow[i] || row[i] != color {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseEncoding validates the --encoding flag value.
+func parseEncoding(encoding string) (string, error) {
+	switch encoding {
+	case "":
+		return "rle-1d", nil
+	case "rle-1d", "rle-2d", "rects":
+		return encoding, nil
+	default:
+		return "", fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
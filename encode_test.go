@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+func TestEncodeRLE2D(t *testing.T) {
+	grid := [][]string{
+		{"black", "black", "white"},
+		{"black", "black", "white"},
+		{"black", "black", "white"},
+		{"white", "white", "white"},
+	}
+
+	rows := encodeRLE2D(grid)
+
+	if rows[0].SameAsRow != 0 {
+		t.Fatalf("row 0: got SameAsRow %d, want 0 (first row always has its own runs)", rows[0].SameAsRow)
+	}
+
+	if rows[1].SameAsRow != 1 || rows[2].SameAsRow != 1 {
+		t.Fatalf("rows 1,2: got SameAsRow %d,%d, want 1,1 (chained off the first occurrence)", rows[1].SameAsRow, rows[2].SameAsRow)
+	}
+
+	if rows[3].SameAsRow != 0 {
+		t.Fatalf("row 3: got SameAsRow %d, want 0 (differs from row 0)", rows[3].SameAsRow)
+	}
+
+	want := []run{{Color: "black", Count: 2}, {Color: "white", Count: 1}}
+	if len(rows[0].Runs) != len(want) || rows[0].Runs[0] != want[0] || rows[0].Runs[1] != want[1] {
+		t.Errorf("row 0 runs = %v, want %v", rows[0].Runs, want)
+	}
+}
+
+func TestEncodeRects(t *testing.T) {
+	grid := [][]string{
+		{"black", "black", "white"},
+		{"black", "black", "white"},
+		{"white", "white", "white"},
+	}
+
+	rects, ok := encodeRects(grid, 0)
+	if !ok {
+		t.Fatal("encodeRects reported overflow for a tiny grid")
+	}
+
+	covered := make([][]bool, len(grid))
+	for y := range covered {
+		covered[y] = make([]bool, len(grid[y]))
+	}
+
+	for _, r := range rects {
+		for dy := 0; dy < r.Height; dy++ {
+			for dx := 0; dx < r.Width; dx++ {
+				y, x := r.Row-1+dy, r.Col-1+dx
+				if covered[y][x] {
+					t.Fatalf("pixel (%d,%d) covered by more than one rect", y, x)
+				}
+
+				covered[y][x] = true
+
+				if grid[y][x] != r.Color {
+					t.Fatalf("rect %+v claims pixel (%d,%d)=%q, got %q", r, y, x, r.Color, grid[y][x])
+				}
+			}
+		}
+	}
+
+	for y, row := range covered {
+		for x, c := range row {
+			if !c {
+				t.Fatalf("pixel (%d,%d) not covered by any rect", y, x)
+			}
+		}
+	}
+}
+
+func TestEncodeRectsMaxRectsFallback(t *testing.T) {
+	// A checkerboard forces one rect per pixel, so a cap of 1 must overflow.
+	grid := [][]string{
+		{"black", "white"},
+		{"white", "black"},
+	}
+
+	if _, ok := encodeRects(grid, 1); ok {
+		t.Fatal("expected encodeRects to report overflow when max-rects is exceeded")
+	}
+}
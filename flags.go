@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/spf13/cobra"
+)
+
+// addImageFlags registers the flags shared by every subcommand that decodes
+// and quantizes an image: the palette/dither pair and the preprocessing
+// pipeline (crop, resize, threshold, invert).
+func addImageFlags(cmd *cobra.Command) {
+	cmd.Flags().String("palette", "bw", "color palette to quantize against: bw, grayscale:N, ansi16, ansi256, websafe, custom:#rrggbb,...")
+	cmd.Flags().String("dither", "none", "pre-dither the image against the palette before encoding: none, floyd-steinberg")
+	cmd.Flags().Int("threshold", defaultThreshold, "gray-value split point for --palette bw (0-255)")
+	cmd.Flags().Bool("auto-threshold", false, "choose the bw threshold automatically via Otsu's method, overriding --threshold")
+	cmd.Flags().Bool("invert", false, "invert the image before quantizing")
+	cmd.Flags().String("crop", "", "crop to X,Y,W,H before resizing")
+	cmd.Flags().String("resize", "", "resize to an exact WxH before quantizing")
+	cmd.Flags().String("resize-filter", "nearest", "resize filter: nearest, bilinear, lanczos")
+	cmd.Flags().Int("max-dimension", 0, "if set and --resize isn't, scale down so the longer side is at most N pixels, preserving aspect ratio")
+}
+
+// preprocessOptionsFromFlags reads the crop/resize/invert flags registered
+// by addImageFlags.
+func preprocessOptionsFromFlags(cmd *cobra.Command) (preprocessOptions, error) {
+	crop, err := cmd.Flags().GetString("crop")
+	if err != nil {
+		return preprocessOptions{}, fmt.Errorf("get crop flag: %w", err)
+	}
+
+	resize, err := cmd.Flags().GetString("resize")
+	if err != nil {
+		return preprocessOptions{}, fmt.Errorf("get resize flag: %w", err)
+	}
+
+	resizeFilter, err := cmd.Flags().GetString("resize-filter")
+	if err != nil {
+		return preprocessOptions{}, fmt.Errorf("get resize-filter flag: %w", err)
+	}
+
+	if _, err := samplerFor(resizeFilter); err != nil {
+		return preprocessOptions{}, err
+	}
+
+	maxDimension, err := cmd.Flags().GetInt("max-dimension")
+	if err != nil {
+		return preprocessOptions{}, fmt.Errorf("get max-dimension flag: %w", err)
+	}
+
+	invert, err := cmd.Flags().GetBool("invert")
+	if err != nil {
+		return preprocessOptions{}, fmt.Errorf("get invert flag: %w", err)
+	}
+
+	return preprocessOptions{
+		Crop:         crop,
+		Resize:       resize,
+		ResizeFilter: resizeFilter,
+		MaxDimension: maxDimension,
+		Invert:       invert,
+	}, nil
+}
+
+// quantizerFromFlags reads the palette/threshold/auto-threshold flags
+// registered by addImageFlags and builds the Quantizer to use against img
+// (img should already be preprocessed, since --auto-threshold measures it).
+func quantizerFromFlags(cmd *cobra.Command, img image.Image) (Quantizer, error) {
+	palette, err := cmd.Flags().GetString("palette")
+	if err != nil {
+		return nil, fmt.Errorf("get palette flag: %w", err)
+	}
+
+	threshold, err := cmd.Flags().GetInt("threshold")
+	if err != nil {
+		return nil, fmt.Errorf("get threshold flag: %w", err)
+	}
+
+	autoThreshold, err := cmd.Flags().GetBool("auto-threshold")
+	if err != nil {
+		return nil, fmt.Errorf("get auto-threshold flag: %w", err)
+	}
+
+	if threshold < 0 || threshold > 255 {
+		return nil, fmt.Errorf("threshold must be in [0,255], got %d", threshold)
+	}
+
+	t := uint8(threshold) //nolint:gosec
+
+	if autoThreshold {
+		t = otsuThreshold(img)
+	}
+
+	return parsePalette(palette, t)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"image"
+
+	"github.com/spf13/cobra"
+
+	"testing"
+)
+
+func TestQuantizerFromFlagsRejectsOutOfRangeThreshold(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+
+	for _, threshold := range []string{"-1", "256", "300"} {
+		cmd := &cobra.Command{Use: "test"}
+		addImageFlags(cmd)
+
+		if err := cmd.Flags().Set("threshold", threshold); err != nil {
+			t.Fatalf("set threshold flag: %v", err)
+		}
+
+		if _, err := quantizerFromFlags(cmd, img); err == nil {
+			t.Errorf("threshold %q: expected an error, got nil", threshold)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Config describes how to build the root handler.
+type Config struct {
+	Level  slog.Level
+	Format string // "text" or "json"
+	Dest   io.Writer
+	// Color enables the colorized text handler. Callers should only set
+	// this when Dest is a TTY and NO_COLOR isn't set.
+	Color bool
+}
+
+// NewHandler builds the slog.Handler the CLI installs as its default,
+// per Config.
+func NewHandler(cfg Config) slog.Handler {
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+
+	switch {
+	case cfg.Format == "json":
+		return slog.NewJSONHandler(cfg.Dest, opts)
+	case cfg.Color:
+		return newColorHandler(cfg.Dest, cfg.Level)
+	default:
+		return slog.NewTextHandler(cfg.Dest, opts)
+	}
+}
+
+// colorHandler is a minimal slog.Handler for interactive terminals: it
+// colorizes the level token and formats timestamps as HH:MM:SS, instead of
+// slog.TextHandler's machine-oriented key=value timestamp.
+type colorHandler struct {
+	dest        io.Writer
+	level       slog.Level
+	attrs       []groupedAttr
+	groupPrefix string
+}
+
+// groupedAttr is an attr bound via WithAttrs, tagged with the dot-joined
+// group path active at the time WithAttrs was called, so Handle can qualify
+// its key even though later WithGroup calls don't retroactively nest it.
+type groupedAttr struct {
+	attr   slog.Attr
+	prefix string
+}
+
+func newColorHandler(dest io.Writer, level slog.Level) *colorHandler {
+	return &colorHandler{dest: dest, level: level}
+}
+
+func (h *colorHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *colorHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+
+	b.WriteString(r.Time.Format("15:04:05"))
+	b.WriteByte(' ')
+	b.WriteString(colorizeLevel(r.Level))
+	b.WriteByte(' ')
+	b.WriteString(r.Message)
+
+	for _, ga := range h.attrs {
+		writeAttr(&b, ga.prefix, ga.attr)
+	}
+
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, h.groupPrefix, a)
+
+		return true
+	})
+
+	b.WriteByte('\n')
+
+	_, err := io.WriteString(h.dest, b.String())
+
+	return err
+}
+
+func (h *colorHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+
+	added := make([]groupedAttr, len(attrs))
+	for i, a := range attrs {
+		added[i] = groupedAttr{attr: a, prefix: h.groupPrefix}
+	}
+
+	next.attrs = append(append([]groupedAttr{}, h.attrs...), added...)
+
+	return &next
+}
+
+func (h *colorHandler) WithGroup(name string) slog.Handler {
+	next := *h
+	next.groupPrefix = h.groupPrefix + name + "."
+
+	return &next
+}
+
+func writeAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	fmt.Fprintf(b, " %s%s=%v", prefix, a.Key, a.Value)
+}
+
+// levelColors maps each level to its ANSI foreground color code.
+var levelColors = map[slog.Level]string{
+	LevelTrace:      "\x1b[90m", // bright black (gray)
+	slog.LevelDebug: "\x1b[36m", // cyan
+	slog.LevelInfo:  "\x1b[32m", // green
+	slog.LevelWarn:  "\x1b[33m", // yellow
+	slog.LevelError: "\x1b[31m", // red
+}
+
+// colorizeLevel renders a level as its name wrapped in its ANSI color.
+func colorizeLevel(level slog.Level) string {
+	name := levelName(level)
+
+	color, ok := levelColors[level]
+	if !ok {
+		color = levelColors[nearestNamedLevel(level)]
+	}
+
+	return color + name + "\x1b[0m"
+}
+
+// levelName renders a level as its upper-case name, including the
+// TRACE level slog itself doesn't define.
+func levelName(level slog.Level) string {
+	switch {
+	case level < slog.LevelDebug:
+		return "TRACE"
+	case level < slog.LevelInfo:
+		return "DEBUG"
+	case level < slog.LevelWarn:
+		return "INFO"
+	case level < slog.LevelError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// nearestNamedLevel buckets an arbitrary level (e.g. slog.LevelInfo+1) down
+// to one of the five levels colorizeLevel has a color for.
+func nearestNamedLevel(level slog.Level) slog.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return LevelTrace
+	case level < slog.LevelInfo:
+		return slog.LevelDebug
+	case level < slog.LevelWarn:
+		return slog.LevelInfo
+	case level < slog.LevelError:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
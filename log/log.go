@@ -0,0 +1,47 @@
+// Package log carries a request-scoped *slog.Logger through a context.Context,
+// and builds the colorized handler the CLI installs as its default logger.
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// LevelTrace is one level more verbose than slog.LevelDebug, for the
+// --log-level=trace option.
+const LevelTrace = slog.LevelDebug - 4
+
+// ParseLevel parses a level name, extending slog.Level's own set
+// (debug/info/warn/error) with "trace".
+func ParseLevel(s string) (slog.Level, error) {
+	if strings.EqualFold(s, "trace") {
+		return LevelTrace, nil
+	}
+
+	var level slog.Level
+
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("parse log level: %w", err)
+	}
+
+	return level, nil
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx that FromContext will resolve to logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx by NewContext, or
+// slog.Default() if ctx carries none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
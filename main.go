@@ -2,30 +2,33 @@ package main
 
 import (
 	"fmt"
-	"image"
-	"image/color"
-	_ "image/png"
 	"log/slog"
 	"os"
-	"strings"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	applog "github.com/tja/image-to-prompt/log"
 )
 
 // Define root command
 var RootCmd = &cobra.Command{
-	Use:               "image-to-prompt [flags] <image-file>",
-	Args:              cobra.ExactArgs(1),
+	Use:               "image-to-prompt",
 	Version:           "0.0.1",
 	CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
 	PersistentPreRunE: setup,
-	RunE:              run,
 }
 
 func init() {
 	// Add flags
-	RootCmd.PersistentFlags().String("log-level", "warn", "verbosity of logging output")
-	RootCmd.PersistentFlags().Bool("log-as-json", false, "change logging format to JSON")
+	RootCmd.PersistentFlags().String("log-level", "warn", "verbosity of logging output: trace, debug, info, warn, error")
+	RootCmd.PersistentFlags().String("log-format", "text", "logging format: text, json")
+	RootCmd.PersistentFlags().Bool("log-as-json", false, "deprecated: use --log-format=json instead")
+	RootCmd.PersistentFlags().String("log-file", "stderr",
+		"log destination: stdout, stderr, or a file path (stdout will interleave with describe/preview output)")
+
+	RootCmd.AddCommand(describeCmd)
+	RootCmd.AddCommand(previewCmd)
 }
 
 // main is the entry point of the application.
@@ -36,121 +39,85 @@ func main() {
 	}
 }
 
-// setup sets up the application.
+// setup sets up the application's logger, both as the slog default (for
+// code that hasn't been threaded through to a context yet) and on the
+// command's context via applog.NewContext, for code that has.
 func setup(cmd *cobra.Command, _ []string) error {
-	// Logging level and format
-	logLevel, err := cmd.Flags().GetString("log-level")
+	logLevel, err := stringFlagOrEnv(cmd, "log-level", "IMAGE_TO_PROMPT_LOG_LEVEL")
 	if err != nil {
 		return fmt.Errorf("get log-level flag: %w", err)
 	}
 
-	logAsJSON, err := cmd.Flags().GetBool("log-as-json")
+	level, err := applog.ParseLevel(logLevel)
 	if err != nil {
-		return fmt.Errorf("get log-as-json flag: %w", err)
+		return err
 	}
 
-	var level slog.Level
-
-	err = level.UnmarshalText([]byte(logLevel))
+	format, err := stringFlagOrEnv(cmd, "log-format", "IMAGE_TO_PROMPT_LOG_FORMAT")
 	if err != nil {
-		return fmt.Errorf("parse log level: %w", err)
+		return fmt.Errorf("get log-format flag: %w", err)
 	}
 
-	var handler slog.Handler
+	logAsJSON, err := cmd.Flags().GetBool("log-as-json")
+	if err != nil {
+		return fmt.Errorf("get log-as-json flag: %w", err)
+	}
 
 	if logAsJSON {
-		handler = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})
-	} else {
-		handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+		format = "json"
 	}
 
-	slog.SetDefault(slog.New(handler))
-
-	return nil
-}
-
-// run loads an image and constructs a run-length encoded prompt describing the image pixel by pixel.
-func run(_ *cobra.Command, args []string) error {
-	// Open image file
-	f, err := os.Open(args[0])
+	logFile, err := stringFlagOrEnv(cmd, "log-file", "IMAGE_TO_PROMPT_LOG_FILE")
 	if err != nil {
-		return fmt.Errorf("open image file: %w", err)
+		return fmt.Errorf("get log-file flag: %w", err)
 	}
 
-	defer f.Close()
-
-	// Decode image
-	img, _, err := image.Decode(f)
+	dest, isTerminal, err := openLogDest(logFile)
 	if err != nil {
-		return fmt.Errorf("decode image: %w", err)
+		return err
 	}
 
-	// Build prompt
-	var prompt strings.Builder
-	bounds := img.Bounds()
-
-	fmt.Fprintf(&prompt, "Please create an image with %d rows and %d columns.\n\n", bounds.Dy(), bounds.Dx())
-
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		row := y - bounds.Min.Y
-		x := bounds.Min.X
-
-		// Determine color of first pixel
-		currentColor := blackOrWhite(img.At(x, y))
-		runLength := 1
-		x++
-
-		// Walk remaining row
-		for x < bounds.Max.X {
-			c := blackOrWhite(img.At(x, y))
-			if c == currentColor {
-				runLength++
-				x++
-
-				continue
-			}
-
-			// Flush current run
-			if runLength == 1 {
-				if runLength == (x - bounds.Min.X) {
-					fmt.Fprintf(&prompt, "Line %d starts with 1 %s pixel, ", row+1, currentColor)
-				} else {
-					fmt.Fprintf(&prompt, "followed by 1 %s pixel, ", currentColor)
-				}
-			} else {
-				if runLength == (x - bounds.Min.X) {
-					fmt.Fprintf(&prompt, "Line %d starts with %d %s pixels, ", row+1, runLength, currentColor)
-				} else {
-					fmt.Fprintf(&prompt, "followed by %d %s pixels, ", runLength, currentColor)
-				}
-			}
-
-			currentColor = c
-			runLength = 1
-			x++
-		}
+	handler := applog.NewHandler(applog.Config{
+		Level:  level,
+		Format: format,
+		Dest:   dest,
+		Color:  isTerminal && format != "json" && os.Getenv("NO_COLOR") == "",
+	})
 
-		// Flush last run of row
-		if runLength == bounds.Dx() {
-			fmt.Fprintf(&prompt, "Line %d only contains %s pixels.\n", row+1, currentColor)
-		} else if runLength == 1 {
-			fmt.Fprintf(&prompt, "and finally 1 %s pixel.\n", currentColor)
-		} else {
-			fmt.Fprintf(&prompt, "and finally %d %s pixels.\n", runLength, currentColor)
-		}
-	}
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
 
-	fmt.Print(prompt.String())
+	cmd.SetContext(applog.NewContext(cmd.Context(), logger))
 
 	return nil
 }
 
-// blackOrWhite returns "black" if the pixel's gray value is <50%, otherwise "white".
-func blackOrWhite(c color.Color) string {
-	gray := color.GrayModel.Convert(c).(color.Gray) //nolint:errcheck
-	if gray.Y < 128 {
-		return "black"
+// stringFlagOrEnv returns env's value if the user didn't explicitly pass
+// flag, so scripts can rely on environment variables instead of flags.
+func stringFlagOrEnv(cmd *cobra.Command, flag, env string) (string, error) {
+	if !cmd.Flags().Changed(flag) {
+		if v, ok := os.LookupEnv(env); ok {
+			return v, nil
+		}
 	}
 
-	return "white"
+	return cmd.Flags().GetString(flag)
+}
+
+// openLogDest resolves the --log-file value to a writer, reporting whether
+// it's an interactive terminal.
+func openLogDest(spec string) (*os.File, bool, error) {
+	switch spec {
+	case "", "stderr":
+		return os.Stderr, term.IsTerminal(int(os.Stderr.Fd())), nil
+	case "stdout":
+		return os.Stdout, term.IsTerminal(int(os.Stdout.Fd())), nil
+	default:
+		f, err := os.OpenFile(spec, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, false, fmt.Errorf("open log file: %w", err)
+		}
+
+		return f, false, nil
+	}
 }
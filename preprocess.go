@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+
+	applog "github.com/tja/image-to-prompt/log"
+)
+
+// defaultThreshold is the gray-value cutoff the original two-color output
+// hard-coded, and the bw palette's default.
+const defaultThreshold = 128
+
+// preprocessOptions bundles every flag that shapes the image before it's
+// quantized and walked.
+type preprocessOptions struct {
+	Crop         string
+	Resize       string
+	ResizeFilter string
+	MaxDimension int
+	Invert       bool
+}
+
+// preprocess applies crop, resize and invert, in that order, to img.
+func preprocess(ctx context.Context, img image.Image, opts preprocessOptions) (image.Image, error) {
+	logger := applog.FromContext(ctx)
+	out := img
+
+	if opts.Crop != "" {
+		cropped, err := cropImage(out, opts.Crop)
+		if err != nil {
+			return nil, fmt.Errorf("crop: %w", err)
+		}
+
+		logger.Debug("cropped image", slog.String("crop", opts.Crop))
+
+		out = cropped
+	}
+
+	switch {
+	case opts.Resize != "":
+		resized, err := resizeImage(out, opts.Resize, opts.ResizeFilter)
+		if err != nil {
+			return nil, fmt.Errorf("resize: %w", err)
+		}
+
+		logger.Debug("resized image", slog.String("resize", opts.Resize), slog.String("filter", opts.ResizeFilter))
+
+		out = resized
+	case opts.MaxDimension > 0:
+		out = resizeToMaxDimension(out, opts.MaxDimension, opts.ResizeFilter)
+		logger.Debug("resized image to max dimension",
+			slog.Int("max_dimension", opts.MaxDimension), slog.String("filter", opts.ResizeFilter))
+	}
+
+	if opts.Invert {
+		out = invertImage(out)
+		logger.Debug("inverted image")
+	}
+
+	return out, nil
+}
+
+// cropImage parses "X,Y,W,H" and returns the cropped sub-image.
+func cropImage(img image.Image, spec string) (image.Image, error) {
+	parts := strings.Split(spec, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("expected X,Y,W,H, got %q", spec)
+	}
+
+	vals := make([]int, 4)
+
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("parse crop component %q: %w", p, err)
+		}
+
+		vals[i] = v
+	}
+
+	x, y, w, h := vals[0], vals[1], vals[2], vals[3]
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("invalid crop size %dx%d", w, h)
+	}
+
+	bounds := img.Bounds()
+	rect := image.Rect(bounds.Min.X+x, bounds.Min.Y+y, bounds.Min.X+x+w, bounds.Min.Y+y+h)
+
+	if !rect.In(bounds) {
+		return nil, fmt.Errorf("crop %v is outside image bounds %v", rect, bounds)
+	}
+
+	type subImager interface {
+		SubImage(image.Rectangle) image.Image
+	}
+
+	if si, ok := img.(subImager); ok {
+		return si.SubImage(rect), nil
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for dy := 0; dy < h; dy++ {
+		for dx := 0; dx < w; dx++ {
+			dst.Set(dx, dy, img.At(rect.Min.X+dx, rect.Min.Y+dy))
+		}
+	}
+
+	return dst, nil
+}
+
+// parseDimensions parses a "WxH" string.
+func parseDimensions(spec string) (int, int, error) {
+	w, h, ok := strings.Cut(spec, "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected WxH, got %q", spec)
+	}
+
+	width, err := strconv.Atoi(w)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parse width: %w", err)
+	}
+
+	height, err := strconv.Atoi(h)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parse height: %w", err)
+	}
+
+	return width, height, nil
+}
+
+// resizeImage parses --resize's "WxH" value and scales img to exactly that
+// size using the requested filter.
+func resizeImage(img image.Image, spec, filter string) (image.Image, error) {
+	width, height, err := parseDimensions(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return scaleImage(img, width, height, filter)
+}
+
+// resizeToMaxDimension scales img down so its longer side is at most max,
+// preserving aspect ratio. Images already within the bound are returned
+// unchanged.
+func resizeToMaxDimension(img image.Image, max int, filter string) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	longest := width
+	if height > longest {
+		longest = height
+	}
+
+	if longest <= max {
+		return img
+	}
+
+	scale := float64(max) / float64(longest)
+	newWidth := clampInt(int(math.Round(float64(width)*scale)), 1, max)
+	newHeight := clampInt(int(math.Round(float64(height)*scale)), 1, max)
+
+	out, err := scaleImage(img, newWidth, newHeight, filter)
+	if err != nil {
+		// filter is already validated by the caller's flag parsing; this
+		// can only happen for width/height <= 0, which can't occur here.
+		return img
+	}
+
+	return out
+}
+
+// scaleImage resizes img to width x height using the named filter.
+func scaleImage(img image.Image, width, height int, filter string) (image.Image, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid target size %dx%d", width, height)
+	}
+
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	sample, err := samplerFor(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	scaleX := float64(srcW) / float64(width)
+	scaleY := float64(srcH) / float64(height)
+
+	for y := 0; y < height; y++ {
+		srcY := (float64(y)+0.5)*scaleY - 0.5
+		for x := 0; x < width; x++ {
+			srcX := (float64(x)+0.5)*scaleX - 0.5
+			dst.Set(x, y, sample(img, bounds, srcX, srcY))
+		}
+	}
+
+	return dst, nil
+}
+
+// sampler evaluates img at a fractional source coordinate.
+type sampler func(img image.Image, bounds image.Rectangle, x, y float64) color.Color
+
+func samplerFor(filter string) (sampler, error) {
+	switch filter {
+	case "", "nearest":
+		return nearestSample, nil
+	case "bilinear":
+		return bilinearSample, nil
+	case "lanczos":
+		return lanczosSample, nil
+	default:
+		return nil, fmt.Errorf("unknown resize filter %q", filter)
+	}
+}
+
+func nearestSample(img image.Image, bounds image.Rectangle, x, y float64) color.Color {
+	sx := clampInt(bounds.Min.X+int(math.Round(x)), bounds.Min.X, bounds.Max.X-1)
+	sy := clampInt(bounds.Min.Y+int(math.Round(y)), bounds.Min.Y, bounds.Max.Y-1)
+
+	return img.At(sx, sy)
+}
+
+func bilinearSample(img image.Image, bounds image.Rectangle, x, y float64) color.Color {
+	x0 := math.Floor(x)
+	y0 := math.Floor(y)
+	fx := x - x0
+	fy := y - y0
+
+	c00 := pixelAt(img, bounds, int(x0), int(y0))
+	c10 := pixelAt(img, bounds, int(x0)+1, int(y0))
+	c01 := pixelAt(img, bounds, int(x0), int(y0)+1)
+	c11 := pixelAt(img, bounds, int(x0)+1, int(y0)+1)
+
+	lerp := func(a, b [4]float64, t float64) [4]float64 {
+		var out [4]float64
+		for i := range out {
+			out[i] = a[i] + (b[i]-a[i])*t
+		}
+
+		return out
+	}
+
+	top := lerp(c00, c10, fx)
+	bottom := lerp(c01, c11, fx)
+	final := lerp(top, bottom, fy)
+
+	return channelsToColor(final)
+}
+
+// lanczosSample applies a separable 2-lobe (a=2) Lanczos kernel.
+func lanczosSample(img image.Image, bounds image.Rectangle, x, y float64) color.Color {
+	const a = 2
+
+	x0 := int(math.Floor(x))
+	y0 := int(math.Floor(y))
+
+	var sum [4]float64
+
+	var weightSum float64
+
+	for dy := -a + 1; dy <= a; dy++ {
+		wy := lanczosKernel(y-float64(y0+dy), a)
+		for dx := -a + 1; dx <= a; dx++ {
+			wx := lanczosKernel(x-float64(x0+dx), a)
+			w := wx * wy
+			weightSum += w
+
+			px := pixelAt(img, bounds, x0+dx, y0+dy)
+			for i := range sum {
+				sum[i] += px[i] * w
+			}
+		}
+	}
+
+	if weightSum != 0 {
+		for i := range sum {
+			sum[i] /= weightSum
+		}
+	}
+
+	return channelsToColor(sum)
+}
+
+func lanczosKernel(x float64, a int) float64 {
+	if x == 0 {
+		return 1
+	}
+
+	if x <= -float64(a) || x >= float64(a) {
+		return 0
+	}
+
+	piX := math.Pi * x
+
+	return float64(a) * math.Sin(piX) * math.Sin(piX/float64(a)) / (piX * piX)
+}
+
+// pixelAt returns the clamped-edge pixel at (x, y) as normalized [0,255] RGBA channels.
+func pixelAt(img image.Image, bounds image.Rectangle, x, y int) [4]float64 {
+	cx := clampInt(bounds.Min.X+x, bounds.Min.X, bounds.Max.X-1)
+	cy := clampInt(bounds.Min.Y+y, bounds.Min.Y, bounds.Max.Y-1)
+
+	r, g, b, al := img.At(cx, cy).RGBA()
+
+	return [4]float64{float64(r >> 8), float64(g >> 8), float64(b >> 8), float64(al >> 8)}
+}
+
+func channelsToColor(c [4]float64) color.Color {
+	return color.NRGBA{
+		R: clampByte(c[0]),
+		G: clampByte(c[1]),
+		B: clampByte(c[2]),
+		A: clampByte(c[3]),
+	}
+}
+
+func clampByte(v float64) uint8 {
+	return uint8(clampInt(int(math.Round(v)), 0, 255)) //nolint:gosec
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+
+	if v > hi {
+		return hi
+	}
+
+	return v
+}
+
+// invertImage returns a copy of img with every pixel's RGB channels inverted.
+func invertImage(img image.Image) image.Image {
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			dst.Set(x, y, color.NRGBA{
+				R: 255 - uint8(r>>8), //nolint:gosec
+				G: 255 - uint8(g>>8), //nolint:gosec
+				B: 255 - uint8(b>>8), //nolint:gosec
+				A: uint8(a >> 8),     //nolint:gosec
+			})
+		}
+	}
+
+	return dst
+}
+
+// otsuThreshold computes the gray-value threshold that maximizes
+// between-class variance, per Otsu's method: for each candidate t, the
+// image is split into a "dark" class [0,t] and a "light" class (t,255];
+// sigma_b^2(t) = w0(t)*w1(t)*(mu0(t)-mu1(t))^2, where w_i and mu_i are each
+// class's cumulative weight and mean. A single pass builds the histogram,
+// then a second pass over t in [0,255] finds its maximum.
+//
+// The returned value is bestT+1, not bestT: callers (bwQuantizer.Name)
+// classify with a strict "gray.Y < threshold", so a threshold of bestT
+// would put the entire dark class at bestT itself into the light bucket.
+// Adding 1 makes the cut fall between the two classes as intended.
+func otsuThreshold(img image.Image) uint8 {
+	var histogram [256]int
+
+	bounds := img.Bounds()
+	total := 0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray := color.GrayModel.Convert(img.At(x, y)).(color.Gray) //nolint:errcheck
+			histogram[gray.Y]++
+			total++
+		}
+	}
+
+	if total == 0 {
+		return defaultThreshold
+	}
+
+	var sumAll float64
+
+	for v, count := range histogram {
+		sumAll += float64(v * count)
+	}
+
+	var (
+		sumDark      float64
+		weightDark   int
+		bestT        int
+		bestVariance float64
+	)
+
+	for t := 0; t < 256; t++ {
+		weightDark += histogram[t]
+		if weightDark == 0 {
+			continue
+		}
+
+		weightLight := total - weightDark
+		if weightLight == 0 {
+			break
+		}
+
+		sumDark += float64(t * histogram[t])
+
+		meanDark := sumDark / float64(weightDark)
+		meanLight := (sumAll - sumDark) / float64(weightLight)
+		diff := meanDark - meanLight
+
+		variance := float64(weightDark) * float64(weightLight) * diff * diff
+		if variance > bestVariance {
+			bestVariance = variance
+			bestT = t
+		}
+	}
+
+	return uint8(clampInt(bestT+1, 0, 255)) //nolint:gosec
+}
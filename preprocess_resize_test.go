@@ -0,0 +1,72 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// flatImage builds a uniform-color image, so any resize filter should
+// reproduce the same color at every output pixel.
+func flatImage(w, h int, c color.Gray) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetGray(x, y, c)
+		}
+	}
+
+	return img
+}
+
+func TestScaleImageFlatColor(t *testing.T) {
+	for _, filter := range []string{"nearest", "bilinear", "lanczos"} {
+		t.Run(filter, func(t *testing.T) {
+			src := flatImage(10, 10, color.Gray{Y: 200})
+
+			out, err := scaleImage(src, 4, 4, filter)
+			if err != nil {
+				t.Fatalf("scaleImage: %v", err)
+			}
+
+			bounds := out.Bounds()
+			if bounds.Dx() != 4 || bounds.Dy() != 4 {
+				t.Fatalf("got %dx%d, want 4x4", bounds.Dx(), bounds.Dy())
+			}
+
+			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					gray := color.GrayModel.Convert(out.At(x, y)).(color.Gray) //nolint:errcheck
+					if gray.Y != 200 {
+						t.Fatalf("pixel (%d,%d) = %d, want 200", x, y, gray.Y)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestScaleImageUnknownFilter(t *testing.T) {
+	src := flatImage(4, 4, color.Gray{Y: 0})
+
+	if _, err := scaleImage(src, 2, 2, "bicubic"); err == nil {
+		t.Fatal("expected an error for an unknown resize filter")
+	}
+}
+
+func TestResizeToMaxDimension(t *testing.T) {
+	src := flatImage(200, 100, color.Gray{Y: 0})
+
+	out := resizeToMaxDimension(src, 50, "nearest")
+	bounds := out.Bounds()
+
+	if bounds.Dx() != 50 || bounds.Dy() != 25 {
+		t.Fatalf("got %dx%d, want 50x25", bounds.Dx(), bounds.Dy())
+	}
+
+	// Already within bound: returned unchanged.
+	small := flatImage(20, 10, color.Gray{Y: 0})
+	if out := resizeToMaxDimension(small, 50, "nearest"); out.Bounds().Dx() != 20 {
+		t.Fatalf("expected image within max dimension to be returned unchanged, got %dx%d", out.Bounds().Dx(), out.Bounds().Dy())
+	}
+}
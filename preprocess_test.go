@@ -0,0 +1,59 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// bimodalImage builds a 20x10 image split into a dark half (gray value lo)
+// and a light half (gray value hi), i.e. a clean bimodal histogram.
+func bimodalImage(lo, hi uint8) image.Image {
+	img := image.NewGray(image.Rect(0, 0, 20, 10))
+
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 20; x++ {
+			v := lo
+			if x >= 10 {
+				v = hi
+			}
+
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+
+	return img
+}
+
+// TestOtsuThresholdBimodal checks that a clean bimodal image is classified
+// back into its two original halves when the computed threshold is fed
+// into bwQuantizer, which classifies with a strict "gray.Y < threshold".
+func TestOtsuThresholdBimodal(t *testing.T) {
+	img := bimodalImage(50, 200)
+
+	threshold := otsuThreshold(img)
+
+	q := bwQuantizer{threshold: threshold}
+	if got := q.Name(color.Gray{Y: 50}); got != "black" {
+		t.Errorf("threshold %d: dark pixel classified as %q, want black", threshold, got)
+	}
+
+	if got := q.Name(color.Gray{Y: 200}); got != "white" {
+		t.Errorf("threshold %d: light pixel classified as %q, want white", threshold, got)
+	}
+}
+
+// TestCropImageRejectsNonPositiveSize checks that a negative or zero W/H is
+// rejected instead of silently normalized by image.Rect into some other
+// (wrong) region.
+func TestCropImageRejectsNonPositiveSize(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+
+	tests := []string{"4,4,-4,-4", "0,0,0,4", "0,0,4,0"}
+
+	for _, spec := range tests {
+		if _, err := cropImage(img, spec); err == nil {
+			t.Errorf("cropImage(%q): expected an error, got nil", spec)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// previewCmd renders the decoded, quantized image to the terminal so users
+// can eyeball what a model will see before sending the prompt.
+var previewCmd = &cobra.Command{
+	Use:   "preview [flags] <image-file>",
+	Short: "Render the quantized image to the terminal",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPreview,
+}
+
+func init() {
+	addImageFlags(previewCmd)
+	previewCmd.Flags().Bool("no-color", false, "disable ANSI color output, even on a TTY")
+}
+
+func runPreview(cmd *cobra.Command, args []string) error {
+	dither, err := cmd.Flags().GetString("dither")
+	if err != nil {
+		return fmt.Errorf("get dither flag: %w", err)
+	}
+
+	noColor, err := cmd.Flags().GetBool("no-color")
+	if err != nil {
+		return fmt.Errorf("get no-color flag: %w", err)
+	}
+
+	preprocessOpts, err := preprocessOptionsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("open image file: %w", err)
+	}
+
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("decode image: %w", err)
+	}
+
+	img, err = preprocess(cmd.Context(), img, preprocessOpts)
+	if err != nil {
+		return fmt.Errorf("preprocess image: %w", err)
+	}
+
+	q, err := quantizerFromFlags(cmd, img)
+	if err != nil {
+		return fmt.Errorf("build quantizer: %w", err)
+	}
+
+	grid, err := quantizeGrid(cmd.Context(), img, q, dither)
+	if err != nil {
+		return fmt.Errorf("quantize image: %w", err)
+	}
+
+	useColor := !noColor && os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(os.Stdout.Fd()))
+
+	var out string
+	if useColor {
+		out = renderANSIBlocks(grid, paletteColorMap(q))
+	} else {
+		out = renderASCIIArt(grid, paletteColorMap(q))
+	}
+
+	fmt.Print(out)
+
+	return nil
+}
+
+// paletteColorMap builds a lookup from palette color name to its RGB value.
+func paletteColorMap(q Quantizer) map[string]color.Color {
+	pc, ok := q.(paletteColorer)
+	if !ok {
+		return nil
+	}
+
+	m := make(map[string]color.Color, len(pc.colors()))
+	for _, c := range pc.colors() {
+		m[c.Name] = c.Color
+	}
+
+	return m
+}
+
+// renderANSIBlocks draws two image rows per terminal line using "▀" (upper
+// half block), with the top pixel as the foreground color and the bottom
+// pixel as the background color.
+func renderANSIBlocks(grid [][]string, colors map[string]color.Color) string {
+	var out strings.Builder
+
+	for y := 0; y < len(grid); y += 2 {
+		for x, topName := range grid[y] {
+			tr, tg, tb := rgb8(colors[topName])
+
+			if y+1 < len(grid) {
+				br, bg, bb := rgb8(colors[grid[y+1][x]])
+				fmt.Fprintf(&out, "\x1b[38;2;%d;%d;%d;48;2;%d;%d;%dm▀", tr, tg, tb, br, bg, bb)
+			} else {
+				fmt.Fprintf(&out, "\x1b[38;2;%d;%d;%dm▀", tr, tg, tb)
+			}
+		}
+
+		out.WriteString("\x1b[0m\n")
+	}
+
+	return out.String()
+}
+
+// asciiRamp is a luminance ramp from darkest to brightest.
+const asciiRamp = " .:-=+*#%@"
+
+// renderASCIIArt draws one character per pixel, picked from asciiRamp by
+// luminance, for non-TTY / NO_COLOR output.
+func renderASCIIArt(grid [][]string, colors map[string]color.Color) string {
+	var out strings.Builder
+
+	for _, line := range grid {
+		for _, name := range line {
+			gray := color.GrayModel.Convert(colors[name]).(color.Gray) //nolint:errcheck
+			idx := int(gray.Y) * (len(asciiRamp) - 1) / 255
+			out.WriteByte(asciiRamp[idx])
+		}
+
+		out.WriteByte('\n')
+	}
+
+	return out.String()
+}
+
+// rgb8 reduces a color.Color to 8-bit-per-channel RGB.
+func rgb8(c color.Color) (r, g, b uint8) {
+	cr, cg, cb, _ := c.RGBA()
+
+	return uint8(cr >> 8), uint8(cg >> 8), uint8(cb >> 8) //nolint:gosec
+}
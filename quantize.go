@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// namedColor pairs a palette entry's RGB value with the name used in prompts.
+type namedColor struct {
+	Name  string
+	Color color.Color
+}
+
+// Quantizer maps an arbitrary pixel color to the name of the nearest color
+// in its palette.
+type Quantizer interface {
+	// Name returns the palette color name nearest to c.
+	Name(c color.Color) string
+}
+
+// paletteColorer is implemented by quantizers that can enumerate their
+// palette, so callers can announce it or dither against it.
+type paletteColorer interface {
+	colors() []namedColor
+}
+
+// parsePalette parses the --palette flag value into a Quantizer. threshold
+// only affects the bw palette; it's ignored by the others.
+func parsePalette(spec string, threshold uint8) (Quantizer, error) {
+	switch {
+	case spec == "bw":
+		return bwQuantizer{threshold: threshold}, nil
+	case spec == "ansi16":
+		return namedPaletteQuantizer{entries: ansi16Palette}, nil
+	case spec == "ansi256":
+		return namedPaletteQuantizer{entries: ansi256Palette()}, nil
+	case spec == "websafe":
+		return namedPaletteQuantizer{entries: websafePalette()}, nil
+	case strings.HasPrefix(spec, "grayscale:"):
+		n, err := strconv.Atoi(strings.TrimPrefix(spec, "grayscale:"))
+		if err != nil {
+			return nil, fmt.Errorf("parse grayscale level count: %w", err)
+		}
+
+		entries, err := grayscalePalette(n)
+		if err != nil {
+			return nil, err
+		}
+
+		return namedPaletteQuantizer{entries: entries}, nil
+	case strings.HasPrefix(spec, "custom:"):
+		entries, err := customPalette(strings.TrimPrefix(spec, "custom:"))
+		if err != nil {
+			return nil, err
+		}
+
+		return namedPaletteQuantizer{entries: entries}, nil
+	default:
+		return nil, fmt.Errorf("unknown palette %q", spec)
+	}
+}
+
+// bwQuantizer reproduces the original two-color behavior, with a
+// configurable split point instead of the original hard-coded 128.
+type bwQuantizer struct {
+	threshold uint8
+}
+
+func (q bwQuantizer) Name(c color.Color) string {
+	gray := color.GrayModel.Convert(c).(color.Gray) //nolint:errcheck
+	if gray.Y < q.threshold {
+		return "black"
+	}
+
+	return "white"
+}
+
+func (bwQuantizer) colors() []namedColor {
+	return []namedColor{
+		{Name: "black", Color: color.Gray{Y: 0}},
+		{Name: "white", Color: color.Gray{Y: 255}},
+	}
+}
+
+// namedPaletteQuantizer picks the nearest entry of a fixed palette by
+// squared Euclidean distance in RGB space.
+type namedPaletteQuantizer struct {
+	entries []namedColor
+}
+
+func (q namedPaletteQuantizer) Name(c color.Color) string {
+	cr, cg, cb, _ := c.RGBA()
+
+	best := 0
+	bestDist := uint64(0)
+
+	for i, e := range q.entries {
+		er, eg, eb, _ := e.Color.RGBA()
+		dr := int64(cr) - int64(er)
+		dg := int64(cg) - int64(eg)
+		db := int64(cb) - int64(eb)
+		dist := uint64(dr*dr + dg*dg + db*db)
+
+		if i == 0 || dist < bestDist {
+			best = i
+			bestDist = dist
+		}
+	}
+
+	return q.entries[best].Name
+}
+
+func (q namedPaletteQuantizer) colors() []namedColor {
+	return q.entries
+}
+
+// grayscalePalette builds n evenly spaced gray levels from black to white.
+func grayscalePalette(n int) ([]namedColor, error) {
+	if n < 2 {
+		return nil, fmt.Errorf("grayscale palette needs at least 2 levels, got %d", n)
+	}
+
+	names := grayscaleNames(n)
+	entries := make([]namedColor, n)
+
+	for i := 0; i < n; i++ {
+		y := uint8(255 * i / (n - 1)) //nolint:gosec
+		entries[i] = namedColor{Name: names[i], Color: color.Gray{Y: y}}
+	}
+
+	return entries, nil
+}
+
+// grayscaleNames picks human-friendly names for n evenly spaced gray levels.
+// Small, common level counts get CSS-style names; larger counts fall back to
+// a percentage-based label.
+func grayscaleNames(n int) []string {
+	switch n {
+	case 2:
+		return []string{"black", "white"}
+	case 3:
+		return []string{"black", "gray", "white"}
+	case 4:
+		return []string{"black", "dimgray", "lightgray", "white"}
+	default:
+		names := make([]string, n)
+		names[0] = "black"
+		names[n-1] = "white"
+
+		for i := 1; i < n-1; i++ {
+			pct := 100 * i / (n - 1)
+			names[i] = fmt.Sprintf("gray%d", pct)
+		}
+
+		return names
+	}
+}
+
+// ansi16Palette is the classic 16-color ANSI palette.
+var ansi16Palette = []namedColor{
+	{Name: "black", Color: color.RGBA{0, 0, 0, 255}},
+	{Name: "maroon", Color: color.RGBA{128, 0, 0, 255}},
+	{Name: "green", Color: color.RGBA{0, 128, 0, 255}},
+	{Name: "olive", Color: color.RGBA{128, 128, 0, 255}},
+	{Name: "navy", Color: color.RGBA{0, 0, 128, 255}},
+	{Name: "purple", Color: color.RGBA{128, 0, 128, 255}},
+	{Name: "teal", Color: color.RGBA{0, 128, 128, 255}},
+	{Name: "silver", Color: color.RGBA{192, 192, 192, 255}},
+	{Name: "gray", Color: color.RGBA{128, 128, 128, 255}},
+	{Name: "red", Color: color.RGBA{255, 0, 0, 255}},
+	{Name: "lime", Color: color.RGBA{0, 255, 0, 255}},
+	{Name: "yellow", Color: color.RGBA{255, 255, 0, 255}},
+	{Name: "blue", Color: color.RGBA{0, 0, 255, 255}},
+	{Name: "fuchsia", Color: color.RGBA{255, 0, 255, 255}},
+	{Name: "aqua", Color: color.RGBA{0, 255, 255, 255}},
+	{Name: "white", Color: color.RGBA{255, 255, 255, 255}},
+}
+
+// ansi256Palette builds the standard xterm 256-color palette: the 16 base
+// colors, a 6x6x6 color cube, and a 24-step grayscale ramp.
+func ansi256Palette() []namedColor {
+	entries := make([]namedColor, 0, 256)
+	entries = append(entries, ansi16Palette...)
+
+	levels := [6]uint8{0, 95, 135, 175, 215, 255}
+	for r := 0; r < 6; r++ {
+		for g := 0; g < 6; g++ {
+			for b := 0; b < 6; b++ {
+				idx := 16 + 36*r + 6*g + b
+				entries = append(entries, namedColor{
+					Name:  fmt.Sprintf("ansi%d", idx),
+					Color: color.RGBA{levels[r], levels[g], levels[b], 255},
+				})
+			}
+		}
+	}
+
+	for i := 0; i < 24; i++ {
+		y := uint8(8 + 10*i) //nolint:gosec
+		entries = append(entries, namedColor{
+			Name:  fmt.Sprintf("ansi%d", 232+i),
+			Color: color.RGBA{y, y, y, 255},
+		})
+	}
+
+	return entries
+}
+
+// websafePalette builds the 216-color web-safe palette (each channel in
+// {0x00, 0x33, 0x66, 0x99, 0xCC, 0xFF}), named by their hex code.
+func websafePalette() []namedColor {
+	steps := [6]uint8{0x00, 0x33, 0x66, 0x99, 0xCC, 0xFF}
+
+	entries := make([]namedColor, 0, 216)
+	for _, r := range steps {
+		for _, g := range steps {
+			for _, b := range steps {
+				entries = append(entries, namedColor{
+					Name:  fmt.Sprintf("#%02x%02x%02x", r, g, b),
+					Color: color.RGBA{r, g, b, 255},
+				})
+			}
+		}
+	}
+
+	return entries
+}
+
+// customPalette parses a comma-separated list of "#rrggbb" colors.
+func customPalette(spec string) ([]namedColor, error) {
+	parts := strings.Split(spec, ",")
+	entries := make([]namedColor, 0, len(parts))
+
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+
+		c, err := parseHexColor(p)
+		if err != nil {
+			return nil, fmt.Errorf("parse custom palette entry %q: %w", p, err)
+		}
+
+		entries = append(entries, namedColor{Name: strings.ToLower(p), Color: c})
+	}
+
+	return entries, nil
+}
+
+// parseHexColor parses a "#rrggbb" string into a color.Color.
+func parseHexColor(s string) (color.Color, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 {
+		return nil, fmt.Errorf("expected #rrggbb, got %q", s)
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	return color.RGBA{
+		R: uint8(v >> 16), //nolint:gosec
+		G: uint8(v >> 8),  //nolint:gosec
+		B: uint8(v),       //nolint:gosec
+		A: 255,
+	}, nil
+}
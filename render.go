@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	applog "github.com/tja/image-to-prompt/log"
+)
+
+// run describes a single run of same-colored pixels within a row.
+type run struct {
+	Color string `json:"color" yaml:"color"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+// encodedRow is one row of a promptDocument under the rle-1d/rle-2d
+// encodings: either its own runs, or (rle-2d only) a reference to the
+// earlier row it's identical to.
+type encodedRow struct {
+	Runs      []run `json:"runs,omitempty" yaml:"runs,omitempty"`
+	SameAsRow int   `json:"same_as_row,omitempty" yaml:"same_as_row,omitempty"` // 1-based
+}
+
+// promptDocument is the palette- and encoding-agnostic description of a
+// quantized image, shared by every Renderer. Exactly one of Rows or Rects
+// is populated, depending on Encoding.
+type promptDocument struct {
+	Width    int          `json:"width" yaml:"width"`
+	Height   int          `json:"height" yaml:"height"`
+	Palette  []string     `json:"palette" yaml:"palette"`
+	Encoding string       `json:"encoding" yaml:"encoding"`
+	Rows     []encodedRow `json:"rows,omitempty" yaml:"rows,omitempty"`
+	Rects    []rect       `json:"rects,omitempty" yaml:"rects,omitempty"`
+}
+
+// newPromptDocument assembles a promptDocument with only its size and
+// palette set; the caller fills in Encoding and Rows or Rects, see
+// encodeRLE1D, encodeRLE2D and encodeRects.
+func newPromptDocument(width, height int, palette []string) promptDocument {
+	return promptDocument{
+		Width:   width,
+		Height:  height,
+		Palette: palette,
+	}
+}
+
+// compressRuns collapses a row of color names into run-length runs.
+func compressRuns(line []string) []run {
+	if len(line) == 0 {
+		return nil
+	}
+
+	runs := make([]run, 0, len(line))
+	current := line[0]
+	count := 1
+
+	for _, c := range line[1:] {
+		if c == current {
+			count++
+
+			continue
+		}
+
+		runs = append(runs, run{Color: current, Count: count})
+		current = c
+		count = 1
+	}
+
+	runs = append(runs, run{Color: current, Count: count})
+
+	return runs
+}
+
+// Renderer turns a quantized image document into prompt text.
+type Renderer interface {
+	Render(ctx context.Context, doc promptDocument) (string, error)
+}
+
+// logRendering emits a debug-level record describing the render about to
+// happen, so downstream issues (e.g. a huge rects count) are diagnosable.
+func logRendering(ctx context.Context, format string, doc promptDocument) {
+	applog.FromContext(ctx).Debug("rendering prompt",
+		slog.String("format", format), slog.String("encoding", doc.Encoding),
+		slog.Int("width", doc.Width), slog.Int("height", doc.Height))
+}
+
+// parseRenderer parses the --format flag value into a Renderer.
+func parseRenderer(format string) (Renderer, error) {
+	switch format {
+	case "", "prose":
+		return proseRenderer{}, nil
+	case "json":
+		return jsonRenderer{}, nil
+	case "yaml":
+		return yamlRenderer{}, nil
+	case "rle":
+		return rleRenderer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// proseRenderer reproduces the original English-prose description.
+type proseRenderer struct{}
+
+func (proseRenderer) Render(ctx context.Context, doc promptDocument) (string, error) {
+	logRendering(ctx, "prose", doc)
+
+	var prompt strings.Builder
+
+	fmt.Fprintf(&prompt, "Please create an image with %d rows and %d columns.\n\n", doc.Height, doc.Width)
+
+	if len(doc.Palette) > 0 {
+		fmt.Fprintf(&prompt, "using colors: %s\n\n", strings.Join(doc.Palette, ", "))
+	}
+
+	switch doc.Encoding {
+	case "rects":
+		renderRectsProse(&prompt, doc.Rects)
+	default:
+		renderRowsProse(&prompt, doc.Rows)
+	}
+
+	return prompt.String(), nil
+}
+
+// renderRowsProse writes the rle-1d/rle-2d row descriptions, collapsing
+// consecutive rows that are identical to an earlier one into a single
+// "rows M-N are identical to row R" sentence.
+func renderRowsProse(prompt *strings.Builder, rows []encodedRow) {
+	for y := 0; y < len(rows); y++ {
+		if rows[y].SameAsRow == 0 {
+			renderRunsProse(prompt, y+1, rows[y].Runs)
+
+			continue
+		}
+
+		// Collapse the whole run of rows duplicating the same source row.
+		ref := rows[y].SameAsRow
+		start := y + 1
+		end := start
+
+		for end+1 <= len(rows) && rows[end].SameAsRow == ref {
+			end++
+		}
+
+		if start == end {
+			fmt.Fprintf(prompt, "Row %d is identical to row %d.\n", start, ref)
+		} else {
+			fmt.Fprintf(prompt, "Rows %d-%d are identical to row %d.\n", start, end, ref)
+		}
+
+		y = end - 1
+	}
+}
+
+// renderRunsProse writes a single row's run-length description.
+func renderRunsProse(prompt *strings.Builder, row int, runs []run) {
+	for i, r := range runs {
+		switch {
+		case i == 0 && len(runs) == 1:
+			fmt.Fprintf(prompt, "Line %d only contains %s pixels.\n", row, r.Color)
+		case i == 0:
+			fmt.Fprintf(prompt, "Line %d starts with %s, ", row, pixelCount(r))
+		case i == len(runs)-1:
+			fmt.Fprintf(prompt, "and finally %s.\n", pixelCount(r))
+		default:
+			fmt.Fprintf(prompt, "followed by %s, ", pixelCount(r))
+		}
+	}
+}
+
+// renderRectsProse writes one sentence per rectangle.
+func renderRectsProse(prompt *strings.Builder, rects []rect) {
+	for _, r := range rects {
+		fmt.Fprintf(prompt, "a %dx%d block of %s at row %d, column %d.\n", r.Width, r.Height, r.Color, r.Row, r.Col)
+	}
+}
+
+// pixelCount formats a run as e.g. "1 black pixel" or "3 white pixels".
+func pixelCount(r run) string {
+	if r.Count == 1 {
+		return fmt.Sprintf("1 %s pixel", r.Color)
+	}
+
+	return fmt.Sprintf("%d %s pixels", r.Count, r.Color)
+}
+
+// jsonRenderer emits the prompt document as JSON.
+type jsonRenderer struct{}
+
+func (jsonRenderer) Render(ctx context.Context, doc promptDocument) (string, error) {
+	logRendering(ctx, "json", doc)
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("marshal json: %w", err)
+	}
+
+	return string(b) + "\n", nil
+}
+
+// yamlRenderer emits the prompt document as YAML.
+type yamlRenderer struct{}
+
+func (yamlRenderer) Render(ctx context.Context, doc promptDocument) (string, error) {
+	logRendering(ctx, "yaml", doc)
+
+	b, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("marshal yaml: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// rleRenderer emits a compact per-line run-length encoding, e.g. "3B7W1B"
+// (or, under the rects encoding, one "row,col,width,height,code" line per
+// block), along with a decoding preamble mapping letter codes to color names.
+type rleRenderer struct{}
+
+func (rleRenderer) Render(ctx context.Context, doc promptDocument) (string, error) {
+	logRendering(ctx, "rle", doc)
+
+	codes := assignRLECodes(doc.Palette)
+
+	var out strings.Builder
+
+	layout := "one line per row, COUNTcode runs"
+
+	switch doc.Encoding {
+	case "rects":
+		layout = "one line per block, ROW,COL,WIDTH,HEIGHT,code"
+	case "rle-2d":
+		layout += ", or =N for a row identical to row N"
+	}
+
+	fmt.Fprintf(&out, "%dx%d image, %s:\n", doc.Width, doc.Height, layout)
+
+	pairs := make([]string, 0, len(codes))
+	for _, name := range doc.Palette {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", codes[name], name))
+	}
+
+	fmt.Fprintf(&out, "%s\n", strings.Join(pairs, ", "))
+
+	switch doc.Encoding {
+	case "rects":
+		for _, r := range doc.Rects {
+			fmt.Fprintf(&out, "%d,%d,%d,%d,%s\n", r.Row, r.Col, r.Width, r.Height, codes[r.Color])
+		}
+	default:
+		for _, row := range doc.Rows {
+			if row.SameAsRow != 0 {
+				fmt.Fprintf(&out, "=%d\n", row.SameAsRow)
+
+				continue
+			}
+
+			for _, r := range row.Runs {
+				fmt.Fprintf(&out, "%d%s", r.Count, codes[r.Color])
+			}
+
+			out.WriteByte('\n')
+		}
+	}
+
+	return out.String(), nil
+}
+
+// assignRLECodes gives every palette color a unique letter code, preferring
+// the color's own initial and falling back to any other unused letter in
+// its name, then to the alphabet in order. Palettes with more than 26
+// colors (ansi256, websafe) overflow into collision-free multi-letter
+// codes ("AA", "AB", ...), the same spreadsheet-column scheme used once
+// the single-letter space is exhausted, rather than colliding on a shared
+// fallback letter.
+func assignRLECodes(palette []string) map[string]string {
+	codes := make(map[string]string, len(palette))
+	used := make(map[string]bool, len(palette))
+	nextCode := columnCodes()
+
+	assign := func(name string) {
+		for i := 0; i < len(name); i++ {
+			c := strings.ToUpper(string(name[i]))
+			if c < "A" || c > "Z" || used[c] {
+				continue
+			}
+
+			codes[name] = c
+			used[c] = true
+
+			return
+		}
+
+		for {
+			c := nextCode()
+			if !used[c] {
+				codes[name] = c
+				used[c] = true
+
+				return
+			}
+		}
+	}
+
+	for _, name := range palette {
+		assign(name)
+	}
+
+	return codes
+}
+
+// columnCodes returns a generator of spreadsheet-column-style letter codes
+// (A, B, ..., Z, AA, AB, ..., ZZ, AAA, ...) in order, used by
+// assignRLECodes to hand out codes beyond a color's own initial.
+func columnCodes() func() string {
+	n := 0
+
+	return func() string {
+		n++
+
+		var b []byte
+
+		for v := n; v > 0; {
+			v--
+			b = append([]byte{byte('A' + v%26)}, b...)
+			v /= 26
+		}
+
+		return string(b)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestAssignRLECodesUnique checks that every palette entry gets a distinct
+// code, including for palettes with more than 26 colors where the
+// single-letter space overflows.
+func TestAssignRLECodesUnique(t *testing.T) {
+	tests := []struct {
+		name    string
+		palette []namedColor
+	}{
+		{"ansi16", ansi16Palette},
+		{"ansi256", ansi256Palette()},
+		{"websafe", websafePalette()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := make([]string, len(tt.palette))
+			for i, c := range tt.palette {
+				names[i] = c.Name
+			}
+
+			codes := assignRLECodes(names)
+
+			seen := make(map[string]string, len(names))
+
+			for _, name := range names {
+				code, ok := codes[name]
+				if !ok || code == "" {
+					t.Fatalf("no code assigned for %q", name)
+				}
+
+				if other, collided := seen[code]; collided && other != name {
+					t.Fatalf("code %q assigned to both %q and %q", code, other, name)
+				}
+
+				seen[code] = name
+			}
+		})
+	}
+}
+
+// TestRLERendererLargePalette exercises the full rle renderer against a
+// 256-color palette end to end, checking that the legend it emits doesn't
+// collapse distinct colors onto the same code.
+func TestRLERendererLargePalette(t *testing.T) {
+	palette := ansi256Palette()
+
+	names := make([]string, len(palette))
+	for i, c := range palette {
+		names[i] = c.Name
+	}
+
+	doc := promptDocument{
+		Width:    len(names),
+		Height:   1,
+		Palette:  names,
+		Encoding: "rle-1d",
+		Rows:     []encodedRow{{Runs: compressRuns(names)}},
+	}
+
+	out, err := (rleRenderer{}).Render(context.Background(), doc)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	lines := strings.SplitN(out, "\n", 3)
+	if len(lines) < 2 {
+		t.Fatalf("output has no legend line:\n%s", out)
+	}
+
+	uniqueCodes := make(map[string]bool, len(names))
+
+	for _, pair := range strings.Split(lines[1], ", ") {
+		code, _, ok := strings.Cut(pair, "=")
+		if !ok {
+			t.Fatalf("malformed legend entry %q", pair)
+		}
+
+		uniqueCodes[code] = true
+	}
+
+	if len(uniqueCodes) != len(names) {
+		t.Fatalf("got %d unique codes for %d colors, output:\n%s", len(uniqueCodes), len(names), out)
+	}
+}